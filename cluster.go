@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serverPopulatedFields are stripped from every object fetched with
+// --from-cluster, mirroring what `kubectl apply` would need removed before
+// the manifest can be re-applied elsewhere.
+var serverPopulatedFields = [][]string{
+	{"status"},
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "managedFields"},
+	{"metadata", "selfLink"},
+}
+
+// ownerResolver walks ownerReferences on the live API, returning the
+// top-level owner's kind and name (e.g. the Deployment behind a ReplicaSet).
+type ownerResolver func(kind, namespace, name string) (topKind, topName string, ok bool)
+
+// buildClusterClients loads kubeconfigPath (falling back to the default
+// loading rules when empty) and returns a dynamic client plus a discovery
+// client for resolving resource kinds/short names to GVRs.
+func buildClusterClients(kubeconfigPath, contextName string) (dynamic.Interface, discovery.DiscoveryInterface, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfigPath != "" {
+		loadingRules.ExplicitPath = kubeconfigPath
+	}
+	overrides := &clientcmd.ConfigOverrides{}
+	if contextName != "" {
+		overrides.CurrentContext = contextName
+	}
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading kubeconfig: %w", err)
+	}
+
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building dynamic client: %w", err)
+	}
+	disc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building discovery client: %w", err)
+	}
+
+	return dyn, disc, nil
+}
+
+// resolveGVR matches resourceArg (a resource name, short name, or kind - case
+// insensitive) against the API server's discovered resources, also reporting
+// whether that resource is namespace-scoped so callers don't have to guess.
+func resolveGVR(disc discovery.DiscoveryInterface, resourceArg string) (schema.GroupVersionResource, bool, error) {
+	resourceArg = strings.ToLower(strings.TrimSpace(resourceArg))
+
+	_, resourceLists, err := discovery.ServerGroupsAndResources(disc)
+	if err != nil {
+		// Partial discovery failures are common (e.g. a broken aggregated
+		// API) and shouldn't prevent matching against what did resolve.
+		if resourceLists == nil {
+			return schema.GroupVersionResource{}, false, fmt.Errorf("discovering server resources: %w", err)
+		}
+	}
+
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range list.APIResources {
+			if matchesResourceArg(apiResource, resourceArg) {
+				return gv.WithResource(apiResource.Name), apiResource.Namespaced, nil
+			}
+		}
+	}
+
+	return schema.GroupVersionResource{}, false, fmt.Errorf("no resource on the server matches %q", resourceArg)
+}
+
+func matchesResourceArg(r metav1.APIResource, resourceArg string) bool {
+	if strings.ToLower(r.Name) == resourceArg || strings.ToLower(r.Kind) == resourceArg || strings.ToLower(r.SingularName) == resourceArg {
+		return true
+	}
+	for _, short := range r.ShortNames {
+		if strings.ToLower(short) == resourceArg {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchClusterManifests lists every requested resource kind from the live
+// cluster, strips server-populated fields, and returns a multi-document YAML
+// stream ready for the same document processing loop --file/--chart feed.
+// It also returns an ownerResolver that parseServiceName can use to walk
+// ownerReferences up to a top-level owner.
+func fetchClusterManifests(kubeconfigPath, contextName, namespace, selector string, resourceArgs []string) (string, ownerResolver, error) {
+	dyn, disc, err := buildClusterClients(kubeconfigPath, contextName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ctx := context.Background()
+	var docs []string
+
+	for _, arg := range resourceArgs {
+		gvr, namespaced, err := resolveGVR(disc, arg)
+		if err != nil {
+			return "", nil, fmt.Errorf("resolving resource %q: %w", arg, err)
+		}
+
+		var list *unstructured.UnstructuredList
+		if namespaced && namespace != "" {
+			list, err = dyn.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		} else {
+			list, err = dyn.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("listing %s: %w", gvr.Resource, err)
+		}
+
+		for i := range list.Items {
+			item := list.Items[i]
+			for _, path := range serverPopulatedFields {
+				unstructured.RemoveNestedField(item.Object, path...)
+			}
+
+			b, err := yaml.Marshal(item.Object)
+			if err != nil {
+				return "", nil, fmt.Errorf("encoding %s/%s: %w", gvr.Resource, item.GetName(), err)
+			}
+			docs = append(docs, string(b))
+		}
+	}
+
+	resolve := buildOwnerResolver(dyn, disc)
+	return strings.Join(docs, "---\n"), resolve, nil
+}
+
+// buildOwnerResolver returns an ownerResolver that walks ownerReferences on
+// the live API until it reaches an object with no further owner, e.g. the
+// Deployment behind a ReplicaSet behind a Pod.
+func buildOwnerResolver(dyn dynamic.Interface, disc discovery.DiscoveryInterface) ownerResolver {
+	return func(kind, namespace, name string) (string, string, bool) {
+		ctx := context.Background()
+		curKind, curName := kind, name
+		// Bound the walk so a reference cycle can't hang the tool.
+		for depth := 0; depth < 10; depth++ {
+			gvr, namespaced, err := resolveGVR(disc, curKind)
+			if err != nil {
+				return "", "", false
+			}
+
+			var obj *unstructured.Unstructured
+			if namespaced && namespace != "" {
+				obj, err = dyn.Resource(gvr).Namespace(namespace).Get(ctx, curName, metav1.GetOptions{})
+			} else {
+				obj, err = dyn.Resource(gvr).Get(ctx, curName, metav1.GetOptions{})
+			}
+			if err != nil {
+				return "", "", false
+			}
+
+			owners := obj.GetOwnerReferences()
+			if len(owners) == 0 {
+				return curKind, curName, true
+			}
+			curKind, curName = owners[0].Kind, owners[0].Name
+		}
+		return curKind, curName, true
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// crdPrinterColumn is one column from a CRD's additionalPrinterColumns. The
+// 'service' grouping heuristic treats a column named "app"/"service"/"owner"
+// as pointing (via its JSONPath) at the owning service for that specific
+// instance, which is more precise than the CRD-wide spec.names.categories.
+type crdPrinterColumn struct {
+	Name     string
+	JSONPath string
+}
+
+// crdEntry is everything we keep around for one group/version/kind after
+// loading --schemas: its validation schema and the grouping hints pulled out
+// of the CRD definition itself.
+type crdEntry struct {
+	schema         *apiextensionsv1.JSONSchemaProps
+	categories     []string
+	printerColumns []crdPrinterColumn
+}
+
+// crdKey identifies a CRD-defined version the same way a resource's
+// apiVersion/kind does.
+func crdKey(group, version, kind string) string {
+	return strings.Join([]string{group, version, kind}, "/")
+}
+
+// crdKeyForResource splits a resource's apiVersion ("group/version", or just
+// "version" for the core group) to build the matching crdKey.
+func crdKeyForResource(apiVersion, kind string) string {
+	group, version := "", apiVersion
+	if idx := strings.Index(apiVersion, "/"); idx != -1 {
+		group, version = apiVersion[:idx], apiVersion[idx+1:]
+	}
+	return crdKey(group, version, kind)
+}
+
+// loadCRDIndex reads every CustomResourceDefinition manifest found at source
+// (a local directory, or an http(s) URL returning a YAML/multi-doc stream)
+// and indexes them by group/version/kind for validation and grouping.
+func loadCRDIndex(source string) (map[string]*crdEntry, error) {
+	var docs [][]byte
+	var err error
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		docs, err = fetchYAMLDocuments(source)
+	} else {
+		docs, err = readYAMLDocumentsFromDir(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]*crdEntry)
+	for _, doc := range docs {
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := sigsyaml.Unmarshal(doc, &crd); err != nil {
+			continue
+		}
+		if crd.Kind != "CustomResourceDefinition" {
+			continue
+		}
+
+		for _, v := range crd.Spec.Versions {
+			var schema *apiextensionsv1.JSONSchemaProps
+			if v.Schema != nil {
+				schema = v.Schema.OpenAPIV3Schema
+			}
+			var cols []crdPrinterColumn
+			for _, col := range v.AdditionalPrinterColumns {
+				cols = append(cols, crdPrinterColumn{Name: col.Name, JSONPath: col.JSONPath})
+			}
+			index[crdKey(crd.Spec.Group, v.Name, crd.Spec.Names.Kind)] = &crdEntry{
+				schema:         schema,
+				categories:     crd.Spec.Names.Categories,
+				printerColumns: cols,
+			}
+		}
+	}
+
+	return index, nil
+}
+
+func fetchYAMLDocuments(url string) ([][]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching CRD schemas from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading CRD schemas from %s: %w", url, err)
+	}
+	return splitYAMLDocuments(body)
+}
+
+func readYAMLDocumentsFromDir(dir string) ([][]byte, error) {
+	var docs [][]byte
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fileDocs, err := splitYAMLDocuments(body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		docs = append(docs, fileDocs...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking schemas directory %s: %w", dir, err)
+	}
+	return docs, nil
+}
+
+// splitYAMLDocuments breaks a multi-document YAML stream into one []byte per
+// document, re-encoded so sigs.k8s.io/yaml (which only understands a single
+// document) can unmarshal each into a typed struct.
+func splitYAMLDocuments(body []byte) ([][]byte, error) {
+	var docs [][]byte
+	decoder := yaml.NewDecoder(bytes.NewReader(body))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := yaml.NewEncoder(&buf).Encode(&node); err != nil {
+			return nil, err
+		}
+		docs = append(docs, buf.Bytes())
+	}
+	return docs, nil
+}
+
+// validateAgainstSchema recursively checks value (as produced by decoding
+// YAML into interface{}) against schema, returning one message per violation.
+// It covers the common subset of OpenAPI v3 actually used by most CRDs:
+// object/array structure, required fields, enum and pattern.
+func validateAgainstSchema(schema *apiextensionsv1.JSONSchemaProps, value interface{}, path string) []string {
+	if schema == nil || value == nil {
+		return nil
+	}
+
+	var errs []string
+
+	if len(schema.Properties) > 0 || schema.Type == "object" {
+		obj, ok := toStringMap(value)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object", path)}
+		}
+		for _, req := range schema.Required {
+			if _, exists := obj[req]; !exists {
+				errs = append(errs, fmt.Sprintf("%s.%s: required field missing", path, req))
+			}
+		}
+		for key, propSchema := range schema.Properties {
+			propSchema := propSchema
+			if v, exists := obj[key]; exists {
+				errs = append(errs, validateAgainstSchema(&propSchema, v, path+"."+key)...)
+			}
+		}
+		return errs
+	}
+
+	if schema.Type == "array" || (schema.Items != nil && schema.Items.Schema != nil) {
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array", path)}
+		}
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range items {
+				errs = append(errs, validateAgainstSchema(schema.Items.Schema, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+		return errs
+	}
+
+	if schema.Type != "" && !matchesScalarType(schema.Type, value) {
+		errs = append(errs, fmt.Sprintf("%s: expected type %q, got %T", path, schema.Type, value))
+	}
+
+	if len(schema.Enum) > 0 && !matchesEnum(schema.Enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: value does not match any allowed enum value", path))
+	}
+
+	if schema.Pattern != "" {
+		if s, ok := value.(string); ok {
+			if matched, _ := regexp.MatchString(schema.Pattern, s); !matched {
+				errs = append(errs, fmt.Sprintf("%s: value %q does not match pattern %q", path, s, schema.Pattern))
+			}
+		}
+	}
+
+	return errs
+}
+
+// evalSimpleJSONPath resolves a CRD additionalPrinterColumns-style JSONPath
+// (e.g. ".spec.owner") against a document decoded into a generic interface{}
+// tree. Only plain dotted field access is supported; that covers every
+// printer column JSONPath used for grouping hints in practice.
+func evalSimpleJSONPath(raw interface{}, path string) (string, bool) {
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return "", false
+	}
+
+	cur := raw
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := toStringMap(cur)
+		if !ok {
+			return "", false
+		}
+		cur, ok = obj[part]
+		if !ok {
+			return "", false
+		}
+	}
+	s, ok := cur.(string)
+	return s, ok
+}
+
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out[fmt.Sprintf("%v", k)] = v
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func matchesScalarType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch value.(type) {
+		case int, int32, int64:
+			return true
+		default:
+			return false
+		}
+	case "number":
+		switch value.(type) {
+		case int, int32, int64, float32, float64:
+			return true
+		default:
+			return false
+		}
+	default:
+		return true
+	}
+}
+
+func matchesEnum(enum []apiextensionsv1.JSON, value interface{}) bool {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return true
+	}
+	for _, e := range enum {
+		if bytes.Equal(bytes.TrimSpace(e.Raw), bytes.TrimSpace(valueJSON)) {
+			return true
+		}
+	}
+	return false
+}
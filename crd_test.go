@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvalSimpleJSONPath(t *testing.T) {
+	raw := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"owner": "checkout",
+		},
+	}
+
+	got, ok := evalSimpleJSONPath(raw, ".spec.owner")
+	if !ok || got != "checkout" {
+		t.Fatalf("got (%q, %v), want (\"checkout\", true)", got, ok)
+	}
+
+	if _, ok := evalSimpleJSONPath(raw, ".spec.missing"); ok {
+		t.Errorf("expected ok=false for a missing field")
+	}
+}
+
+func TestLoadCRDIndexCapturesPrinterColumnsAndCategories(t *testing.T) {
+	doc := []byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    categories:
+      - platform
+  versions:
+    - name: v1
+      additionalPrinterColumns:
+        - name: Owner
+          type: string
+          jsonPath: .spec.owner
+`)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "widget-crd.yaml"), doc, 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	index, err := loadCRDIndex(dir)
+	if err != nil {
+		t.Fatalf("loadCRDIndex: %v", err)
+	}
+
+	entry, ok := index[crdKey("example.com", "v1", "Widget")]
+	if !ok {
+		t.Fatalf("expected an entry for example.com/v1 Widget")
+	}
+	if len(entry.categories) != 1 || entry.categories[0] != "platform" {
+		t.Errorf("categories: got %v, want [platform]", entry.categories)
+	}
+	if len(entry.printerColumns) != 1 || entry.printerColumns[0].Name != "Owner" || entry.printerColumns[0].JSONPath != ".spec.owner" {
+		t.Errorf("printerColumns: got %#v, want [{Owner .spec.owner}]", entry.printerColumns)
+	}
+}
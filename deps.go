@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyOrderRank returns the safe-apply bucket for a Kind: Namespaces first,
+// then CRDs, then RBAC, then config, then Services, then workloads, then
+// everything else (Ingresses, HPAs, ...).
+func applyOrderRank(kind string) int {
+	switch kind {
+	case "Namespace":
+		return 0
+	case "CustomResourceDefinition":
+		return 1
+	case "ServiceAccount", "Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding":
+		return 2
+	case "ConfigMap", "Secret":
+		return 3
+	case "Service":
+		return 4
+	case "Deployment", "StatefulSet", "DaemonSet", "Job", "CronJob":
+		return 5
+	default:
+		return 6
+	}
+}
+
+// installOrderEntry is one resource recorded for install-order.yaml.
+type installOrderEntry struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+	File      string `yaml:"file"`
+}
+
+// installOrderState accumulates every resource written during a run so a
+// safe apply order can be emitted once processing is done.
+type installOrderState struct {
+	entries []installOrderEntry
+}
+
+func newInstallOrderState() *installOrderState {
+	return &installOrderState{}
+}
+
+func (s *installOrderState) add(kind, name, namespace, file string) {
+	s.entries = append(s.entries, installOrderEntry{Kind: kind, Name: name, Namespace: namespace, File: file})
+}
+
+// write sorts the recorded resources into a safe kubectl-apply order and
+// emits install-order.yaml listing them under "order:".
+func (s *installOrderState) write(outputDir string) error {
+	if len(s.entries) == 0 {
+		return nil
+	}
+
+	sorted := make([]installOrderEntry, len(s.entries))
+	copy(sorted, s.entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return applyOrderRank(sorted[i].Kind) < applyOrderRank(sorted[j].Kind)
+	})
+
+	doc := struct {
+		Order []installOrderEntry `yaml:"order"`
+	}{Order: sorted}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("encoding install-order.yaml: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outputDir, "install-order.yaml"), buf.Bytes(), 0644)
+}
+
+// referenceKey identifies a ConfigMap/Secret/ServiceAccount that a workload
+// refers to, so it can be grouped into the same service directory.
+type referenceKey struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// workloadKinds are the resources findReferences knows how to read a pod
+// spec out of, either directly (Pod), via spec.jobTemplate.spec.template.spec
+// (CronJob) or via spec.template.spec (the rest).
+var workloadKinds = map[string]bool{
+	"Pod":         true,
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+	"Job":         true,
+	"CronJob":     true,
+	"ReplicaSet":  true,
+}
+
+// referencableKinds are the kinds findReferences produces referenceKeys for,
+// and so the only kinds whose directory placement --format=service will
+// override based on who references them.
+var referencableKinds = map[string]bool{
+	"ConfigMap":      true,
+	"Secret":         true,
+	"ServiceAccount": true,
+}
+
+// podSpecNode locates a resource's pod spec: spec.template.spec for
+// Deployments/StatefulSets/etc., spec.jobTemplate.spec.template.spec for
+// CronJobs, or plain spec for a bare Pod.
+func podSpecNode(root *yaml.Node) *yaml.Node {
+	spec := mappingLookup(root, "spec")
+	if spec == nil {
+		return nil
+	}
+	if jobTmpl := mappingLookup(spec, "jobTemplate"); jobTmpl != nil {
+		if jobSpec := mappingLookup(jobTmpl, "spec"); jobSpec != nil {
+			spec = jobSpec
+		}
+	}
+	if tmpl := mappingLookup(spec, "template"); tmpl != nil {
+		if tmplSpec := mappingLookup(tmpl, "spec"); tmplSpec != nil {
+			return tmplSpec
+		}
+	}
+	return spec
+}
+
+// findReferences walks a workload's pod spec and returns every
+// ConfigMap/Secret/ServiceAccount it refers to via serviceAccountName,
+// volumes, envFrom, or imagePullSecrets.
+func findReferences(root *yaml.Node, namespace string) []referenceKey {
+	podSpec := podSpecNode(root)
+	if podSpec == nil {
+		return nil
+	}
+
+	var refs []referenceKey
+	addRef := func(kind string, nameNode *yaml.Node) {
+		if nameNode != nil && nameNode.Value != "" {
+			refs = append(refs, referenceKey{Kind: kind, Namespace: namespace, Name: nameNode.Value})
+		}
+	}
+
+	if sa := mappingLookup(podSpec, "serviceAccountName"); sa != nil {
+		addRef("ServiceAccount", sa)
+	}
+
+	if pullSecrets := mappingLookup(podSpec, "imagePullSecrets"); pullSecrets != nil && pullSecrets.Kind == yaml.SequenceNode {
+		for _, item := range pullSecrets.Content {
+			addRef("Secret", mappingLookup(item, "name"))
+		}
+	}
+
+	if volumes := mappingLookup(podSpec, "volumes"); volumes != nil && volumes.Kind == yaml.SequenceNode {
+		for _, vol := range volumes.Content {
+			if cm := mappingLookup(vol, "configMap"); cm != nil {
+				addRef("ConfigMap", mappingLookup(cm, "name"))
+			}
+			if sec := mappingLookup(vol, "secret"); sec != nil {
+				addRef("Secret", mappingLookup(sec, "secretName"))
+			}
+		}
+	}
+
+	for _, containerField := range []string{"containers", "initContainers"} {
+		containers := mappingLookup(podSpec, containerField)
+		if containers == nil || containers.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, container := range containers.Content {
+			envFrom := mappingLookup(container, "envFrom")
+			if envFrom == nil || envFrom.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, ef := range envFrom.Content {
+				if cmRef := mappingLookup(ef, "configMapRef"); cmRef != nil {
+					addRef("ConfigMap", mappingLookup(cmRef, "name"))
+				}
+				if secRef := mappingLookup(ef, "secretRef"); secRef != nil {
+					addRef("Secret", mappingLookup(secRef, "name"))
+				}
+			}
+		}
+	}
+
+	return refs
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplyOrderRank(t *testing.T) {
+	kinds := []string{"Deployment", "Namespace", "Service", "ConfigMap", "ClusterRoleBinding", "CustomResourceDefinition", "Ingress"}
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return applyOrderRank(kinds[i]) < applyOrderRank(kinds[j])
+	})
+
+	want := []string{"Namespace", "CustomResourceDefinition", "ClusterRoleBinding", "ConfigMap", "Service", "Deployment", "Ingress"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("sorted order: got %v, want %v", kinds, want)
+	}
+}
+
+// decodeDoc parses input and returns its root mapping node, matching what
+// findReferences receives as node.Content[0] from the main document loop.
+// Decoding into a *yaml.Node yields a DocumentNode wrapper, which
+// mappingLookup's MappingNode check would miss, so the root mapping is
+// taken from its Content[0].
+func decodeDoc(t *testing.T, input string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.NewDecoder(strings.NewReader(input)).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return doc.Content[0]
+}
+
+func TestFindReferencesDeployment(t *testing.T) {
+	input := `
+spec:
+  template:
+    spec:
+      serviceAccountName: checkout-sa
+      imagePullSecrets:
+        - name: registry-creds
+      volumes:
+        - name: config
+          configMap:
+            name: checkout-config
+        - name: creds
+          secret:
+            secretName: checkout-secret
+      containers:
+        - name: app
+          envFrom:
+            - configMapRef:
+                name: checkout-env
+            - secretRef:
+                name: checkout-secret-env
+`
+	root := decodeDoc(t, input)
+	got := findReferences(root, "prod")
+
+	want := []referenceKey{
+		{Kind: "ServiceAccount", Namespace: "prod", Name: "checkout-sa"},
+		{Kind: "Secret", Namespace: "prod", Name: "registry-creds"},
+		{Kind: "ConfigMap", Namespace: "prod", Name: "checkout-config"},
+		{Kind: "Secret", Namespace: "prod", Name: "checkout-secret"},
+		{Kind: "ConfigMap", Namespace: "prod", Name: "checkout-env"},
+		{Kind: "Secret", Namespace: "prod", Name: "checkout-secret-env"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findReferences: got %#v, want %#v", got, want)
+	}
+}
+
+func TestFindReferencesBarePod(t *testing.T) {
+	input := `
+spec:
+  serviceAccountName: worker-sa
+`
+	root := decodeDoc(t, input)
+	got := findReferences(root, "default")
+
+	want := []referenceKey{{Kind: "ServiceAccount", Namespace: "default", Name: "worker-sa"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findReferences: got %#v, want %#v", got, want)
+	}
+}
+
+func TestFindReferencesNoPodSpec(t *testing.T) {
+	input := "metadata:\n  name: cm\n"
+	root := decodeDoc(t, input)
+
+	if got := findReferences(root, "default"); got != nil {
+		t.Errorf("expected nil references for a document with no pod spec, got %#v", got)
+	}
+}
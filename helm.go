@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// renderHelmChart renders chartPath with the given values file under
+// releaseName/namespace, the same way `helm template` does, and returns the
+// resulting multi-document YAML manifest stream. It never talks to a live
+// cluster: install.ClientOnly makes this equivalent to a local template pass.
+func renderHelmChart(chartPath, valuesFile, releaseName, namespace string) (string, error) {
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return "", fmt.Errorf("loading chart %s: %w", chartPath, err)
+	}
+
+	vals := map[string]interface{}{}
+	if valuesFile != "" {
+		vals, err = chartutil.ReadValuesFile(valuesFile)
+		if err != nil {
+			return "", fmt.Errorf("reading values file %s: %w", valuesFile, err)
+		}
+	}
+
+	settings := cli.New()
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, "memory", func(string, ...interface{}) {}); err != nil {
+		return "", fmt.Errorf("initializing helm action config: %w", err)
+	}
+
+	install := action.NewInstall(actionConfig)
+	install.ReleaseName = releaseName
+	install.Namespace = namespace
+	install.ClientOnly = true
+	install.DryRun = true
+	install.DisableHooks = true
+	install.IncludeCRDs = true
+
+	rel, err := install.Run(chrt, vals)
+	if err != nil {
+		return "", fmt.Errorf("rendering chart %s: %w", chartPath, err)
+	}
+
+	return rel.Manifest, nil
+}
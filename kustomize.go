@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationDir accumulates the resource and patch file names that belong
+// to a single kustomize directory (the output root, or one kind/service
+// subdirectory) so a kustomization.yaml can be written for it once every
+// document has been processed.
+type kustomizationDir struct {
+	resources []string
+	patches   []string
+}
+
+// kustomizeState tracks every directory that needs a kustomization.yaml,
+// keyed by its path relative to --outdir ("" for the output root itself).
+type kustomizeState struct {
+	dirs map[string]*kustomizationDir
+}
+
+func newKustomizeState() *kustomizeState {
+	return &kustomizeState{dirs: make(map[string]*kustomizationDir)}
+}
+
+func (s *kustomizeState) dir(relDir string) *kustomizationDir {
+	d, ok := s.dirs[relDir]
+	if !ok {
+		d = &kustomizationDir{}
+		s.dirs[relDir] = d
+	}
+	return d
+}
+
+func (s *kustomizeState) addResource(relDir, filename string) {
+	d := s.dir(relDir)
+	d.resources = append(d.resources, filename)
+}
+
+func (s *kustomizeState) addPatch(relDir, filename string) {
+	d := s.dir(relDir)
+	d.patches = append(d.patches, filename)
+}
+
+// write emits a kustomization.yaml for every subdirectory that received
+// resources or patches, plus a root kustomization.yaml that lists those
+// subdirectories (or, if nothing was nested, the resource files directly).
+func (s *kustomizeState) write(outputDir string) error {
+	root := s.dir("")
+
+	var subdirs []string
+	for relDir := range s.dirs {
+		if relDir == "" {
+			continue
+		}
+		subdirs = append(subdirs, relDir)
+	}
+	sort.Strings(subdirs)
+
+	for _, relDir := range subdirs {
+		d := s.dirs[relDir]
+		if err := writeKustomizationFile(filepath.Join(outputDir, relDir), d.resources, d.patches); err != nil {
+			return err
+		}
+		root.resources = append(root.resources, relDir)
+	}
+
+	return writeKustomizationFile(outputDir, root.resources, root.patches)
+}
+
+func writeKustomizationFile(dir string, resources, patches []string) error {
+	if len(resources) == 0 && len(patches) == 0 {
+		return nil
+	}
+
+	type kustomization struct {
+		ApiVersion string   `yaml:"apiVersion"`
+		Kind       string   `yaml:"kind"`
+		Resources  []string `yaml:"resources,omitempty"`
+		Patches    []string `yaml:"patches,omitempty"`
+	}
+
+	k := kustomization{
+		ApiVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+		Patches:    patches,
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&k); err != nil {
+		return fmt.Errorf("encoding kustomization.yaml for %s: %w", dir, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "kustomization.yaml"), buf.Bytes(), 0644)
+}
+
+// baseResourceKey identifies a resource the way --base matching does: by
+// apiVersion, kind, namespace and name.
+func baseResourceKey(apiVersion, kind, namespace, name string) string {
+	return strings.Join([]string{apiVersion, kind, namespace, name}, "/")
+}
+
+// loadBaseResources walks baseDir, decoding every YAML document it finds and
+// indexing its root mapping node by apiVersion/kind/namespace/name so
+// --base can be diffed against incoming documents.
+func loadBaseResources(baseDir string) (map[string]*yaml.Node, error) {
+	index := make(map[string]*yaml.Node)
+
+	err := filepath.WalkDir(baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		decoder := yaml.NewDecoder(file)
+		for {
+			var doc yaml.Node
+			if err := decoder.Decode(&doc); err != nil {
+				if err.Error() == "EOF" {
+					break
+				}
+				log.Printf("Error parsing base document %s: %v", path, err)
+				break
+			}
+			if len(doc.Content) == 0 {
+				continue
+			}
+			root := doc.Content[0]
+
+			var resource K8sResource
+			if err := root.Decode(&resource); err != nil {
+				log.Printf("Error extracting resource info from base document %s: %v", path, err)
+				continue
+			}
+			if resource.Kind == "" || resource.Metadata.Name == "" {
+				continue
+			}
+
+			key := baseResourceKey(resource.ApiVersion, resource.Kind, resource.Metadata.Namespace, resource.Metadata.Name)
+			index[key] = root
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking base directory %s: %w", baseDir, err)
+	}
+
+	return index, nil
+}
+
+// diffAgainstBase compares incoming against base and returns a strategic
+// merge patch node containing the resource's identity fields plus every
+// top-level (and nested mapping) field that differs from base. The second
+// return value is false when incoming is identical to base, meaning no
+// patch is needed at all.
+func diffAgainstBase(base, incoming *yaml.Node) (*yaml.Node, bool) {
+	patch, changed := diffMappingNode(base, incoming)
+	if patch == nil {
+		patch = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	ensureIdentityFields(patch, incoming)
+	return patch, changed
+}
+
+// identityKeys are always copied into the patch verbatim so kustomize can
+// target the right object, even if they didn't change.
+var identityKeys = []string{"apiVersion", "kind"}
+
+func ensureIdentityFields(patch *yaml.Node, incoming *yaml.Node) {
+	for _, key := range identityKeys {
+		if mappingLookup(patch, key) == nil {
+			if v := mappingLookup(incoming, key); v != nil {
+				mappingSet(patch, key, v)
+			}
+		}
+	}
+
+	metadata := mappingLookup(incoming, "metadata")
+	if metadata == nil {
+		return
+	}
+	patchMetadata := mappingLookup(patch, "metadata")
+	if patchMetadata == nil {
+		patchMetadata = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		mappingSet(patch, "metadata", patchMetadata)
+	}
+	for _, key := range []string{"name", "namespace"} {
+		if mappingLookup(patchMetadata, key) == nil {
+			if v := mappingLookup(metadata, key); v != nil {
+				mappingSet(patchMetadata, key, v)
+			}
+		}
+	}
+}
+
+// diffMappingNode recursively compares two mapping nodes and returns a node
+// holding only the keys from incoming whose value differs from base (or is
+// new). Non-mapping values that differ are copied wholesale rather than
+// merged field-by-field.
+func diffMappingNode(base, incoming *yaml.Node) (*yaml.Node, bool) {
+	if incoming == nil {
+		return nil, false
+	}
+	if base == nil {
+		return incoming, true
+	}
+	if incoming.Kind != yaml.MappingNode || base.Kind != yaml.MappingNode {
+		if nodesEqual(base, incoming) {
+			return nil, false
+		}
+		return incoming, true
+	}
+
+	result := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	changed := false
+
+	for i := 0; i < len(incoming.Content); i += 2 {
+		key := incoming.Content[i]
+		val := incoming.Content[i+1]
+		baseVal := mappingLookup(base, key.Value)
+
+		if baseVal != nil && val.Kind == yaml.MappingNode && baseVal.Kind == yaml.MappingNode {
+			sub, subChanged := diffMappingNode(baseVal, val)
+			if subChanged {
+				mappingSet(result, key.Value, sub)
+				changed = true
+			}
+			continue
+		}
+
+		if baseVal == nil || !nodesEqual(baseVal, val) {
+			mappingSet(result, key.Value, val)
+			changed = true
+		}
+	}
+
+	return result, changed
+}
+
+func mappingLookup(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func mappingSet(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	node.Content = append(node.Content, keyNode, value)
+}
+
+// nodesEqual compares two arbitrary nodes by their re-encoded YAML text,
+// which is cheap enough for patch-sized documents and sidesteps having to
+// special-case every scalar tag and sequence ordering rule by hand.
+func nodesEqual(a, b *yaml.Node) bool {
+	return encodeNodeForDiff(a) == encodeNodeForDiff(b)
+}
+
+func encodeNodeForDiff(n *yaml.Node) string {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	if err := encoder.Encode(n); err != nil {
+		return ""
+	}
+	encoder.Close()
+	return buf.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML parses a YAML mapping document and returns its root mapping
+// node, the same shape diffMappingNode receives from the main document
+// loop. Decoding into a *yaml.Node yields a DocumentNode wrapper, so the
+// root mapping is taken from its Content[0]; passing the wrapper itself
+// would make every Kind check in diffMappingNode miss.
+func decodeYAML(t *testing.T, input string) *yaml.Node {
+	t.Helper()
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(strings.NewReader(input)).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return doc.Content[0]
+}
+
+func decodeNode(t *testing.T, node *yaml.Node) map[string]interface{} {
+	t.Helper()
+	if node == nil {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := node.Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return out
+}
+
+func TestDiffMappingNodeNewKeyOnly(t *testing.T) {
+	base := decodeYAML(t, "replicas: 1\n")
+	incoming := decodeYAML(t, "replicas: 1\nimage: nginx:1.0\n")
+
+	patch, changed := diffMappingNode(base, incoming)
+	if !changed {
+		t.Fatalf("expected changed=true")
+	}
+
+	want := map[string]interface{}{"image": "nginx:1.0"}
+	if got := decodeNode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("patch: got %#v, want %#v", got, want)
+	}
+}
+
+func TestDiffMappingNodeNoChange(t *testing.T) {
+	base := decodeYAML(t, "replicas: 1\nimage: nginx:1.0\n")
+	incoming := decodeYAML(t, "replicas: 1\nimage: nginx:1.0\n")
+
+	patch, changed := diffMappingNode(base, incoming)
+	if changed {
+		t.Fatalf("expected changed=false, got patch %#v", decodeNode(t, patch))
+	}
+}
+
+func TestDiffMappingNodeNestedMappingOnlyChangedSubkey(t *testing.T) {
+	base := decodeYAML(t, "spec:\n  replicas: 1\n  template:\n    foo: bar\n")
+	incoming := decodeYAML(t, "spec:\n  replicas: 2\n  template:\n    foo: bar\n")
+
+	patch, changed := diffMappingNode(base, incoming)
+	if !changed {
+		t.Fatalf("expected changed=true")
+	}
+
+	want := map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": 2},
+	}
+	if got := decodeNode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("patch: got %#v, want %#v", got, want)
+	}
+}
+
+func TestDiffMappingNodeNonMappingValueReplacedWholesale(t *testing.T) {
+	base := decodeYAML(t, "tags:\n  - a\n  - b\n")
+	incoming := decodeYAML(t, "tags:\n  - a\n  - c\n")
+
+	patch, changed := diffMappingNode(base, incoming)
+	if !changed {
+		t.Fatalf("expected changed=true")
+	}
+
+	want := map[string]interface{}{"tags": []interface{}{"a", "c"}}
+	if got := decodeNode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("patch: got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stripPathSegment represents one dot-separated component of a --strip
+// expression, e.g. the "containers[*]" in "spec.template.spec.containers[*].imagePullPolicy".
+type stripPathSegment struct {
+	key string // literal map key to match, or "*" to match any key
+	all bool   // segment ended in "[*]": descend into every element of the sequence value
+}
+
+// defaultStripPaths are the fields kubectl commonly injects into live/applied
+// manifests. Used by --strip-defaults.
+var defaultStripPaths = []string{
+	"status",
+	"metadata.uid",
+	"metadata.resourceVersion",
+	"metadata.generation",
+	"metadata.creationTimestamp",
+	"metadata.managedFields",
+	`metadata.annotations."kubectl.kubernetes.io/last-applied-configuration"`,
+}
+
+// parseStripPath splits a dotted path expression into segments. Segments may
+// be double-quoted to allow literal dots (e.g. annotation keys) and may carry
+// a trailing "[*]" to mean "every element of this sequence".
+func parseStripPath(path string) []stripPathSegment {
+	var raw []string
+	var cur strings.Builder
+	inQuotes := false
+	for _, r := range path {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			cur.WriteRune(r)
+		case r == '.' && !inQuotes:
+			raw = append(raw, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	raw = append(raw, cur.String())
+
+	segments := make([]stripPathSegment, 0, len(raw))
+	for _, seg := range raw {
+		seg = strings.TrimSpace(seg)
+		all := false
+		if strings.HasSuffix(seg, "[*]") {
+			all = true
+			seg = strings.TrimSuffix(seg, "[*]")
+		}
+		seg = strings.Trim(seg, `"`)
+		if seg == "" {
+			continue
+		}
+		segments = append(segments, stripPathSegment{key: seg, all: all})
+	}
+	return segments
+}
+
+// stripDocument removes every path in paths from the document's root mapping.
+// doc is expected to be a *yaml.Node of Kind yaml.DocumentNode, as produced by
+// decoder.Decode.
+func stripDocument(doc *yaml.Node, paths [][]stripPathSegment) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+	for _, segments := range paths {
+		stripPath(root, segments)
+	}
+}
+
+// stripPath walks node following segments and deletes the final matched
+// key/value pair(s), preserving comments and ordering on everything else.
+func stripPath(node *yaml.Node, segments []stripPathSegment) {
+	if len(segments) == 0 || node == nil {
+		return
+	}
+	seg := segments[0]
+	rest := segments[1:]
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valNode := node.Content[i+1]
+			if seg.key != "*" && keyNode.Value != seg.key {
+				continue
+			}
+
+			if seg.all {
+				if len(rest) == 0 {
+					valNode.Content = nil
+				} else {
+					stripSequence(valNode, rest)
+				}
+				continue
+			}
+
+			if len(rest) == 0 {
+				// Delete this key/value pair from the mapping.
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+				i -= 2
+				continue
+			}
+
+			stripPath(valNode, rest)
+		}
+	case yaml.SequenceNode:
+		// A bare "*" segment reaching a sequence stands for "every index of
+		// this sequence" and must be consumed here: the rest of the path
+		// applies to each element, not the "*" itself (which would otherwise
+		// get reinterpreted as "any map key" once we recurse into a mapping
+		// element below).
+		if seg.key == "*" {
+			stripSequence(node, rest)
+		}
+	}
+}
+
+// stripSequence applies segments to every element of a sequence node, used
+// for "[*]" path segments.
+func stripSequence(node *yaml.Node, segments []stripPathSegment) {
+	if node.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, item := range node.Content {
+		stripPath(item, segments)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stripAndDecode parses input, strips path from it, and decodes the result
+// into a generic map/slice tree so assertions don't depend on yaml.v3's
+// exact re-encoding (indentation, quoting, ...). It goes through
+// yaml.Decoder.Decode the same way the main document loop does, which
+// yields the DocumentNode wrapper that stripDocument expects.
+func stripAndDecode(t *testing.T, input, path string) map[string]interface{} {
+	t.Helper()
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(strings.NewReader(input)).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	stripDocument(&doc, [][]stripPathSegment{parseStripPath(path)})
+
+	var out map[string]interface{}
+	if err := doc.Content[0].Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return out
+}
+
+func TestStripPathBareWildcardTraversesSequence(t *testing.T) {
+	input := "items:\n  - name: a\n  - name: b\n"
+
+	got := stripAndDecode(t, input, "items.*.name")
+	want := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items.*.name: got %#v, want %#v", got, want)
+	}
+}
+
+func TestStripPathBracketWildcardTraversesSequence(t *testing.T) {
+	input := "items:\n  - name: a\n  - name: b\n"
+
+	got := stripAndDecode(t, input, "items[*].name")
+	want := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items[*].name: got %#v, want %#v", got, want)
+	}
+}
+
+func TestStripPathLiteralKey(t *testing.T) {
+	input := "metadata:\n  uid: abc\n  name: foo\n"
+
+	got := stripAndDecode(t, input, "metadata.uid")
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "foo",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata.uid: got %#v, want %#v", got, want)
+	}
+}
+
+func TestStripPathAnnotationWithDots(t *testing.T) {
+	input := "metadata:\n  annotations:\n    kubectl.kubernetes.io/last-applied-configuration: \"{}\"\n    keep: me\n"
+
+	got := stripAndDecode(t, input, `metadata.annotations."kubectl.kubernetes.io/last-applied-configuration"`)
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"keep": "me",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotation strip: got %#v, want %#v", got, want)
+	}
+}
@@ -19,9 +19,13 @@ type K8sResource struct {
 	ApiVersion string `yaml:"apiVersion"`
 	Kind       string `yaml:"kind"`
 	Metadata   struct {
-		Name      string            `yaml:"name"`
-		Namespace string            `yaml:"namespace"`
-		Labels    map[string]string `yaml:"labels"`
+		Name            string            `yaml:"name"`
+		Namespace       string            `yaml:"namespace"`
+		Labels          map[string]string `yaml:"labels"`
+		OwnerReferences []struct {
+			Kind string `yaml:"kind"`
+			Name string `yaml:"name"`
+		} `yaml:"ownerReferences"`
 	} `yaml:"metadata"`
 	Spec struct {
 		Selector struct {
@@ -35,8 +39,15 @@ type K8sResource struct {
 	} `yaml:"spec"`
 }
 
-// parseServiceName attempts to extract a service name from a resource
-func parseServiceName(resource *K8sResource) string {
+// parseServiceName attempts to extract a service name from a resource.
+// resolveOwner is only non-nil in --from-cluster mode, where ownerReferences
+// can be walked against the live API to find the top-level owner (e.g. the
+// Deployment behind a ReplicaSet); pass nil for file/chart-based input.
+// printerColumns and raw carry a matching CRD's additionalPrinterColumns and
+// this document decoded generically, so an "app"/"service"/"owner" column
+// can be resolved to an actual value for this instance; raw may be nil if
+// the caller has no use for printer-column hints (or none were declared).
+func parseServiceName(resource *K8sResource, resolveOwner ownerResolver, crdCategories []string, printerColumns []crdPrinterColumn, raw interface{}) string {
 	// Check for common app labels
 	appLabels := []string{"app", "app.kubernetes.io/name", "k8s-app"}
 
@@ -84,10 +95,58 @@ func parseServiceName(resource *K8sResource) string {
 		}
 	}
 
+	// For live-cluster input, fall back to walking ownerReferences up to the
+	// top-level owner (e.g. a Deployment behind a ReplicaSet) before giving up.
+	if resolveOwner != nil {
+		for _, ref := range resource.Metadata.OwnerReferences {
+			if _, topName, ok := resolveOwner(ref.Kind, resource.Metadata.Namespace, ref.Name); ok {
+				return topName
+			}
+		}
+	}
+
+	// For custom resources, fall back to CRD-declared grouping hints: an
+	// additionalPrinterColumns entry naming the owning app/service/owner for
+	// this specific instance, or (less precise, but CRD-wide) the first
+	// category declared in spec.names.categories.
+	if raw != nil {
+		for _, col := range printerColumns {
+			switch strings.ToLower(col.Name) {
+			case "app", "service", "owner":
+				if value, ok := evalSimpleJSONPath(raw, col.JSONPath); ok && value != "" {
+					return value
+				}
+			}
+		}
+	}
+	if len(crdCategories) > 0 {
+		return crdCategories[0]
+	}
+
 	// If we can't determine the service, default to "common" or "other"
 	return "common"
 }
 
+// stringSliceFlag implements flag.Value to collect a flag that can be
+// repeated on the command line, splitting each occurrence on commas.
+type stringSliceFlag struct {
+	values []string
+}
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(s.values, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			s.values = append(s.values, part)
+		}
+	}
+	return nil
+}
+
 // printUsage prints the usage help message
 func printUsage() {
 	fmt.Println("YAML Parser - splits multi-document YAML into separate files")
@@ -96,27 +155,79 @@ func printUsage() {
 	fmt.Println("  yaml_parser --file=input.yaml --outdir=./output")
 	fmt.Println("\nParameters:")
 	fmt.Println("  --file      Input YAML file path (if not specified, stdin will be used)")
+	fmt.Println("  --chart     Path to a Helm chart to render instead of reading --file/stdin")
+	fmt.Println("  --values    Values file to use when rendering --chart")
+	fmt.Println("  --release   Release name to use when rendering --chart (default \"release\")")
+	fmt.Println("  --namespace Namespace to use when rendering --chart or with --from-cluster (default \"default\")")
+	fmt.Println("  --from-cluster  List resources from a live cluster instead of --file/--chart/stdin")
+	fmt.Println("  --kubeconfig    kubeconfig path to use with --from-cluster (default: standard loading rules)")
+	fmt.Println("  --context       kubeconfig context to use with --from-cluster")
+	fmt.Println("  --selector      Label selector to filter resources listed with --from-cluster")
+	fmt.Println("  --resource      Resource kind to list with --from-cluster (repeatable, comma-separated)")
 	fmt.Println("  --outdir    Output directory for parsed manifests (required)")
 	fmt.Println("  --remove    Patterns to remove from each manifest (regex, comma-separated)")
+	fmt.Println("  --strip     YAML/JSONPath-like field paths to prune from the node tree")
+	fmt.Println("              before re-encoding (comma-separated, '*' matches any key")
+	fmt.Println("              or sequence index), e.g. 'metadata.creationTimestamp'")
+	fmt.Println("  --strip-defaults  Also strip the fields kubectl typically injects")
+	fmt.Println("                    (status, metadata.uid, resourceVersion, generation,")
+	fmt.Println("                    creationTimestamp, managedFields, last-applied-configuration)")
 	fmt.Println("  --format    Output filename format:")
 	fmt.Println("              'kind-name'   - Flat structure with kind-name.yaml files (default)")
 	fmt.Println("              'kind/name'   - Group by kind in directories")
 	fmt.Println("              'service'     - Group by service in directories")
+	fmt.Println("              'kustomize'   - Emit kustomization.yaml files (see --group below)")
+	fmt.Println("  --group     Directory grouping to use with --format=kustomize: 'kind' (default)")
+	fmt.Println("              or 'service' - each gets its own per-subdirectory kustomization.yaml")
+	fmt.Println("  --base      Base directory to diff against with --format=kustomize;")
+	fmt.Println("              unchanged resources are skipped and changed ones become")
+	fmt.Println("              patch files listed under 'patches:' instead of 'resources:'")
+	fmt.Println("  --schemas   Directory or URL of CustomResourceDefinition manifests to load;")
+	fmt.Println("              used to validate custom resources and improve 'service' grouping")
+	fmt.Println("  --strict    Exit non-zero if any resource fails --schemas validation")
+	fmt.Println("\nEvery run also emits install-order.yaml in --outdir, listing every saved")
+	fmt.Println("resource in a safe apply order (Namespaces, CRDs, RBAC, config, Services,")
+	fmt.Println("workloads, then everything else). With --format=service, a Deployment's")
+	fmt.Println("referenced ConfigMaps/Secrets/ServiceAccounts are grouped into its directory.")
 	fmt.Println("\nExamples:")
 	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests")
 	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --remove=\"status:.*,generation:.*\"")
+	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --strip-defaults")
+	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --strip=\"spec.template.spec.containers[*].imagePullPolicy\"")
 	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --format=kind/name")
 	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --format=service")
 	fmt.Println("  cat 1.yaml | yaml_parser --outdir=./manifests")
+	fmt.Println("  yaml_parser --chart=./mychart --values=values.yaml --release=foo --namespace=bar --outdir=./manifests --format=service")
+	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --format=kustomize")
+	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --format=kustomize --base=./base")
+	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --format=kustomize --group=service")
+	fmt.Println("  yaml_parser --from-cluster --namespace=prod --resource=deploy,svc,cm --outdir=./manifests --format=service")
+	fmt.Println("  yaml_parser --file=1.yaml --outdir=./manifests --schemas=./crds --strict")
 	fmt.Println("")
 }
 
 func main() {
 	// Define command line flags
 	inputFile := flag.String("file", "", "Input YAML file path (if not specified, stdin will be used)")
+	chartPath := flag.String("chart", "", "Path to a Helm chart to render instead of reading --file/stdin")
+	valuesFile := flag.String("values", "", "Values file to use when rendering --chart")
+	releaseName := flag.String("release", "release", "Release name to use when rendering --chart")
+	namespace := flag.String("namespace", "default", "Namespace to use when rendering --chart or listing with --from-cluster")
+	fromCluster := flag.Bool("from-cluster", false, "List resources from a live cluster instead of reading --file/--chart/stdin")
+	kubeconfigPath := flag.String("kubeconfig", "", "Path to a kubeconfig file to use with --from-cluster (defaults to the usual kubeconfig loading rules)")
+	kubeContext := flag.String("context", "", "kubeconfig context to use with --from-cluster")
+	selector := flag.String("selector", "", "Label selector to filter resources listed with --from-cluster")
+	var resourceKinds stringSliceFlag
+	flag.Var(&resourceKinds, "resource", "Resource kind to list with --from-cluster (repeatable, comma-separated, e.g. --resource=deploy,svc,cm)")
 	outputDir := flag.String("outdir", "", "Output directory for parsed manifests")
 	removePatterns := flag.String("remove", "", "Comma-separated patterns to remove from each manifest")
-	format := flag.String("format", "kind-name", "Output filename format: 'kind-name', 'kind/name', or 'service'")
+	stripPaths := flag.String("strip", "", "Comma-separated YAML/JSONPath-like field paths to prune before re-encoding")
+	stripDefaults := flag.Bool("strip-defaults", false, "Strip the fields kubectl typically injects (status, uid, resourceVersion, generation, creationTimestamp, managedFields, last-applied-configuration)")
+	format := flag.String("format", "kind-name", "Output filename format: 'kind-name', 'kind/name', 'service', or 'kustomize'")
+	baseDir := flag.String("base", "", "Base directory to diff against when --format=kustomize, producing patch files instead of full manifests")
+	group := flag.String("group", "kind", "Directory grouping to use with --format=kustomize: 'kind' or 'service'")
+	schemasSource := flag.String("schemas", "", "Directory or URL of CustomResourceDefinition manifests to validate custom resources against")
+	strict := flag.Bool("strict", false, "Exit non-zero if any resource fails --schemas validation")
 	help := flag.Bool("help", false, "Show usage information")
 	flag.Parse()
 
@@ -136,14 +247,39 @@ func main() {
 		"kind-name": true,
 		"kind/name": true,
 		"service":   true,
+		"kustomize": true,
 	}
 	if !validFormats[*format] {
-		log.Fatalf("Invalid format option: %s. Must be 'kind-name', 'kind/name', or 'service'", *format)
+		log.Fatalf("Invalid format option: %s. Must be 'kind-name', 'kind/name', 'service', or 'kustomize'", *format)
+	}
+	if *group != "kind" && *group != "service" {
+		log.Fatalf("Invalid group option: %s. Must be 'kind' or 'service'", *group)
 	}
 
 	// Prepare input source
 	var input io.ReadCloser
-	if *inputFile != "" {
+	var resolveOwner ownerResolver
+	if *fromCluster {
+		if len(resourceKinds.values) == 0 {
+			log.Fatalf("--from-cluster requires at least one --resource")
+		}
+		log.Printf("Listing %s from cluster (namespace=%s, selector=%q)...", strings.Join(resourceKinds.values, ","), *namespace, *selector)
+		manifest, resolve, err := fetchClusterManifests(*kubeconfigPath, *kubeContext, *namespace, *selector, resourceKinds.values)
+		if err != nil {
+			log.Fatalf("Error listing resources from cluster: %v", err)
+		}
+		input = io.NopCloser(strings.NewReader(manifest))
+		resolveOwner = resolve
+	} else if *chartPath != "" {
+		// Render the chart and feed the resulting manifest stream into the
+		// same document processing loop as --file/stdin.
+		log.Printf("Rendering Helm chart %s (release=%s, namespace=%s)...", *chartPath, *releaseName, *namespace)
+		manifest, err := renderHelmChart(*chartPath, *valuesFile, *releaseName, *namespace)
+		if err != nil {
+			log.Fatalf("Error rendering Helm chart: %v", err)
+		}
+		input = io.NopCloser(strings.NewReader(manifest))
+	} else if *inputFile != "" {
 		// Read from file
 		file, err := os.Open(*inputFile)
 		if err != nil {
@@ -164,6 +300,15 @@ func main() {
 		log.Printf("Reading YAML from stdin...")
 	}
 
+	// Buffer the whole input so --format=service can make a pass over every
+	// document up front to find cross-resource references before the main
+	// per-document loop decides where each file goes.
+	inputBytes, err := io.ReadAll(input)
+	input.Close()
+	if err != nil {
+		log.Fatalf("Error reading input: %v", err)
+	}
+
 	// Create the output directory if it doesn't exist
 	if err := os.RemoveAll(*outputDir); err != nil {
 		log.Fatalf("Error removing previous output directory: %v", err)
@@ -184,8 +329,94 @@ func main() {
 		}
 	}
 
+	// Prepare --strip / --strip-defaults field paths
+	var stripRawPaths []string
+	if *stripDefaults {
+		stripRawPaths = append(stripRawPaths, defaultStripPaths...)
+	}
+	if *stripPaths != "" {
+		stripRawPaths = append(stripRawPaths, strings.Split(*stripPaths, ",")...)
+	}
+	var stripParsedPaths [][]stripPathSegment
+	for _, p := range stripRawPaths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		stripParsedPaths = append(stripParsedPaths, parseStripPath(p))
+	}
+
+	// When emitting kustomize output, load the base directory (if any) to
+	// diff incoming documents against, and track emitted files so a
+	// kustomization.yaml can be written per directory once we're done.
+	var baseIndex map[string]*yaml.Node
+	if *format == "kustomize" && *baseDir != "" {
+		var err error
+		baseIndex, err = loadBaseResources(*baseDir)
+		if err != nil {
+			log.Fatalf("Error loading base directory: %v", err)
+		}
+	}
+	kustomize := newKustomizeState()
+
+	// Load CRD schemas once at startup so they can be reused across documents
+	// for both validation and the 'service' grouping heuristic.
+	var crdIndex map[string]*crdEntry
+	if *schemasSource != "" {
+		var err error
+		crdIndex, err = loadCRDIndex(*schemasSource)
+		if err != nil {
+			log.Fatalf("Error loading --schemas: %v", err)
+		}
+		log.Printf("Loaded %d CRD version(s) from %s", len(crdIndex), *schemasSource)
+	}
+	validatedCount, invalidCount := 0, 0
+	installOrder := newInstallOrderState()
+
+	// For --format=service (and --format=kustomize --group=service, which
+	// shares the same serviceNameFor grouping), make an up-front pass over
+	// every workload document to find which ConfigMaps/Secrets/ServiceAccounts
+	// it references, so those get grouped into the workload's service
+	// directory even when parseServiceName alone would route them to
+	// "common".
+	referencedService := make(map[referenceKey]string)
+	if *format == "service" || (*format == "kustomize" && *group == "service") {
+		refDecoder := yaml.NewDecoder(bytes.NewReader(inputBytes))
+		for {
+			var node yaml.Node
+			if err := refDecoder.Decode(&node); err != nil {
+				break
+			}
+			var resource K8sResource
+			if err := node.Decode(&resource); err != nil || len(node.Content) == 0 {
+				continue
+			}
+			if !workloadKinds[resource.Kind] {
+				continue
+			}
+			var crdCategories []string
+			var crdPrinterColumns []crdPrinterColumn
+			if crdIndex != nil {
+				if entry, ok := crdIndex[crdKeyForResource(resource.ApiVersion, resource.Kind)]; ok {
+					crdCategories = entry.categories
+					crdPrinterColumns = entry.printerColumns
+				}
+			}
+			var raw interface{}
+			if len(crdPrinterColumns) > 0 {
+				_ = node.Decode(&raw)
+			}
+			serviceName := parseServiceName(&resource, resolveOwner, crdCategories, crdPrinterColumns, raw)
+			for _, ref := range findReferences(node.Content[0], resource.Metadata.Namespace) {
+				if _, exists := referencedService[ref]; !exists {
+					referencedService[ref] = serviceName
+				}
+			}
+		}
+	}
+
 	// Create a YAML decoder
-	decoder := yaml.NewDecoder(input)
+	decoder := yaml.NewDecoder(bytes.NewReader(inputBytes))
 
 	// Count of successfully parsed manifests
 	count := 0
@@ -202,6 +433,12 @@ func main() {
 			continue
 		}
 
+		// Prune any requested fields from the node tree before we do
+		// anything else with the document.
+		if len(stripParsedPaths) > 0 {
+			stripDocument(&node, stripParsedPaths)
+		}
+
 		// Extract the resource identification (apiVersion, kind, name)
 		var resource K8sResource
 		if err := node.Decode(&resource); err != nil {
@@ -215,7 +452,57 @@ func main() {
 			continue
 		}
 
+		// Validate against a matching CRD schema, if --schemas was loaded
+		// and one is registered for this resource's group/version/kind.
+		var crdCategories []string
+		var crdPrinterColumns []crdPrinterColumn
+		var generic interface{}
+		var genericDecoded bool
+		if crdIndex != nil {
+			if entry, ok := crdIndex[crdKeyForResource(resource.ApiVersion, resource.Kind)]; ok {
+				crdCategories = entry.categories
+				crdPrinterColumns = entry.printerColumns
+				if entry.schema != nil || len(entry.printerColumns) > 0 {
+					if err := node.Decode(&generic); err != nil {
+						log.Printf("Error decoding document %d for validation: %v", i, err)
+					} else {
+						genericDecoded = true
+					}
+				}
+				if entry.schema != nil && genericDecoded {
+					validatedCount++
+					if errs := validateAgainstSchema(entry.schema, generic, resource.Kind); len(errs) > 0 {
+						invalidCount++
+						log.Printf("Document %d (%s/%s) failed schema validation:", i, resource.Kind, resource.Metadata.Name)
+						for _, e := range errs {
+							log.Printf("  - %s", e)
+						}
+					}
+				}
+			}
+		}
+		var crdRaw interface{}
+		if genericDecoded {
+			crdRaw = generic
+		}
+
+		// Determine the service name a resource would be grouped under in
+		// --format=service: parseServiceName's own guess, overridden when a
+		// workload elsewhere in the stream references this ConfigMap/Secret/
+		// ServiceAccount. Shared with --format=kustomize --group=service.
+		serviceNameFor := func() string {
+			name := parseServiceName(&resource, resolveOwner, crdCategories, crdPrinterColumns, crdRaw)
+			if referencableKinds[resource.Kind] {
+				key := referenceKey{Kind: resource.Kind, Namespace: resource.Metadata.Namespace, Name: resource.Metadata.Name}
+				if owner, ok := referencedService[key]; ok {
+					name = owner
+				}
+			}
+			return name
+		}
+
 		var filePath string
+		var relDir, filename string
 
 		if *format == "kind-name" {
 			// Create filename: kind-name.yaml in lowercase
@@ -235,7 +522,7 @@ func main() {
 			filePath = filepath.Join(kindDirPath, filename)
 		} else if *format == "service" {
 			// Determine the service name
-			serviceName := parseServiceName(&resource)
+			serviceName := serviceNameFor()
 
 			// Create a directory for the service
 			serviceDirPath := filepath.Join(*outputDir, serviceName)
@@ -247,13 +534,48 @@ func main() {
 			// Use kind-name for the filename to avoid conflicts
 			filename := strings.ToLower(fmt.Sprintf("%s-%s.yaml", resource.Kind, resource.Metadata.Name))
 			filePath = filepath.Join(serviceDirPath, filename)
+		} else if *format == "kustomize" {
+			// Group by kind (the directory layout kustomize bases
+			// conventionally use) or by service, per --group.
+			if *group == "service" {
+				relDir = serviceNameFor()
+			} else {
+				relDir = strings.ToLower(resource.Kind)
+			}
+			groupDirPath := filepath.Join(*outputDir, relDir)
+			if err := os.MkdirAll(groupDirPath, 0755); err != nil {
+				log.Printf("Error creating directory for %s: %v", relDir, err)
+				continue
+			}
+
+			filename = strings.ToLower(fmt.Sprintf("%s-%s.yaml", resource.Kind, resource.Metadata.Name))
+			filePath = filepath.Join(groupDirPath, filename)
+		}
+
+		// Node to encode, and whether it's a patch against --base rather
+		// than the full document.
+		encNode := &node
+		isPatch := false
+		if *format == "kustomize" && baseIndex != nil && len(node.Content) > 0 {
+			key := baseResourceKey(resource.ApiVersion, resource.Kind, resource.Metadata.Namespace, resource.Metadata.Name)
+			if baseVal, ok := baseIndex[key]; ok {
+				patch, changed := diffAgainstBase(baseVal, node.Content[0])
+				if !changed {
+					log.Printf("Document %d (%s %s) matches base, skipping", i, resource.Kind, resource.Metadata.Name)
+					continue
+				}
+				encNode = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{patch}}
+				filename = strings.TrimSuffix(filename, ".yaml") + ".patch.yaml"
+				filePath = filepath.Join(filepath.Dir(filePath), filename)
+				isPatch = true
+			}
 		}
 
 		// Re-encode the entire document as clean YAML
 		var buf bytes.Buffer
 		encoder := yaml.NewEncoder(&buf)
 		encoder.SetIndent(2) // Set indentation level
-		if err := encoder.Encode(&node); err != nil {
+		if err := encoder.Encode(encNode); err != nil {
 			log.Printf("Error encoding document %d: %v", i, err)
 			continue
 		}
@@ -282,7 +604,37 @@ func main() {
 
 		count++
 		fmt.Printf("Saved document to %s\n", filePath)
+
+		if relFile, err := filepath.Rel(*outputDir, filePath); err == nil {
+			installOrder.add(resource.Kind, resource.Metadata.Name, resource.Metadata.Namespace, relFile)
+		}
+
+		if *format == "kustomize" {
+			if isPatch {
+				kustomize.addPatch(relDir, filename)
+			} else {
+				kustomize.addResource(relDir, filename)
+			}
+		}
+	}
+
+	if *format == "kustomize" {
+		if err := kustomize.write(*outputDir); err != nil {
+			log.Fatalf("Error writing kustomization.yaml: %v", err)
+		}
+	}
+
+	if err := installOrder.write(*outputDir); err != nil {
+		log.Fatalf("Error writing install-order.yaml: %v", err)
 	}
 
 	fmt.Printf("Parsing complete! Saved %d manifests.\n", count)
+
+	if crdIndex != nil {
+		fmt.Printf("Validated %d custom resource(s) against --schemas: %d passed, %d failed.\n",
+			validatedCount, validatedCount-invalidCount, invalidCount)
+		if *strict && invalidCount > 0 {
+			log.Fatalf("--strict: %d custom resource(s) failed schema validation", invalidCount)
+		}
+	}
 }